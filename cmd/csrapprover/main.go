@@ -0,0 +1,64 @@
+// Command csrapprover runs the CSR auto-approval controller in-cluster,
+// continuously performing the approve step that main() otherwise does once
+// per CLI invocation.
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/hamido95/go-kubernetes-user-creator/pkg/csrapprover"
+)
+
+func main() {
+	var (
+		signerName         string
+		usernamePrefix     string
+		maxExpiry          time.Duration
+		allowListNamespace string
+		allowListName      string
+		metricsAddr        string
+	)
+
+	flag.StringVar(&signerName, "signer-name", "kubernetes.io/kube-apiserver-client", "Only consider CSRs with this SignerName (alongside --username-prefix) when they lack the auto-approve annotation")
+	flag.StringVar(&usernamePrefix, "username-prefix", "", "Only consider CSRs whose Username has this prefix, when matching by --signer-name")
+	flag.DurationVar(&maxExpiry, "max-expiry", 24*time.Hour, "Deny CSRs requesting an expiry longer than this")
+	flag.StringVar(&allowListNamespace, "allowlist-configmap-namespace", "kube-system", "Namespace of the ConfigMap enumerating allowed CommonName/Organization combinations")
+	flag.StringVar(&allowListName, "allowlist-configmap", "", "Name of the ConfigMap enumerating allowed CommonName/Organization combinations; empty disables the allow-list check")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8443", "Address the metrics endpoint binds to")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New())
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Metrics: metricsserver.Options{BindAddress: metricsAddr},
+	})
+	if err != nil {
+		ctrl.Log.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	reconciler := &csrapprover.Reconciler{
+		Client:             mgr.GetClient(),
+		Recorder:           mgr.GetEventRecorderFor("csrapprover"),
+		SignerName:         signerName,
+		UsernamePrefix:     usernamePrefix,
+		MaxExpiry:          maxExpiry,
+		AllowListConfigMap: types.NamespacedName{Namespace: allowListNamespace, Name: allowListName},
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		ctrl.Log.Error(err, "unable to create controller", "controller", "CertificateSigningRequest")
+		os.Exit(1)
+	}
+
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		ctrl.Log.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
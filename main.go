@@ -2,386 +2,407 @@ package main
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
-	certificatesv1 "k8s.io/api/certificates/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	userprovisionerv1alpha1 "github.com/hamido95/go-kubernetes-user-creator/pkg/apis/userprovisioner/v1alpha1"
+	"github.com/hamido95/go-kubernetes-user-creator/pkg/lifecycle"
+	"github.com/hamido95/go-kubernetes-user-creator/pkg/signer"
+	"github.com/hamido95/go-kubernetes-user-creator/pkg/userprovision"
 )
 
 var (
-	username            string
-	dirName             string
-	userExpirationSec   int64
-	kubeConfigPath      string
-	clusterName         string
-	roleRules           string
-	clusterRoleRules    string
-	roleBindings        string
-	clusterRoleBindings string
+	specPath       string
+	dryRun         bool
+	prune          bool
+	kubeConfigPath string
+	clusterName    string
+
+	username   string
+	groupsFlag string
+
+	shortLived         bool
+	ttl                time.Duration
+	intermediateCAPath string
+
+	signerBackend     string
+	caSecretNamespace string
+	caSecretName      string
 )
 
 func init() {
-	flag.StringVar(&username, "username", "supmu", "The username for the new user")
-	flag.StringVar(&dirName, "dir", "/home/user/devtest/kubeuser/psp/supmu", "The directory to store keys and certs")
-	flag.Int64Var(&userExpirationSec, "expiration", 315569520, "User expiration in seconds")
+	flag.StringVar(&specPath, "spec", "", "Path to a users.yaml declaring the users to provision and their RBAC grants")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print the YAML that would be applied for --spec without hitting the API")
+	flag.BoolVar(&prune, "prune", false, "Delete objects previously owned by a user (label userprovisioner.k8s/owner=<username>) that are no longer in --spec")
 	flag.StringVar(&kubeConfigPath, "kubeconfig", "/home/user/.kube/psp-config", "Path to the kubeconfig file")
 	flag.StringVar(&clusterName, "cluster", "kubernetes", "Kubernetes cluster name")
-	flag.StringVar(&roleRules, "role-rules", "", "Comma-separated list of role rules in the format 'namespace:apiGroups:resources:verbs:resourceNames'")
-	flag.StringVar(&clusterRoleRules, "clusterrole-rules", "", "Comma-separated list of cluster role rules in the format 'apiGroups:resources:verbs:resourceNames'")
-	flag.StringVar(&roleBindings, "role-bindings", "", "Comma-separated list of role bindings in the format 'namespace:roleName'")
-	flag.StringVar(&clusterRoleBindings, "clusterrole-bindings", "", "Comma-separated list of cluster role bindings in the format 'clusterRoleName'")
-	flag.Parse()
-}
 
-func parseRules(rulesStr string) []v1.PolicyRule {
-	var rules []v1.PolicyRule
-	for _, ruleStr := range strings.Split(rulesStr, ",") {
-		parts := strings.Split(ruleStr, ":")
-		if len(parts) < 4 {
-			fmt.Println("Invalid rule format")
-			continue
-		}
+	flag.StringVar(&username, "username", "", "Provision a single user ad hoc instead of reading --spec (pairs with --short-lived --ttl and --groups)")
+	flag.StringVar(&groupsFlag, "groups", "", "Comma-separated groups for --username, e.g. sso:alice,dev (ignored with --spec, which sets groups per user)")
 
-		rule := v1.PolicyRule{
-			APIGroups: strings.Split(parts[1], ";"),
-			Resources: strings.Split(parts[2], ";"),
-			Verbs:     strings.Split(parts[3], ";"),
-		}
-		if len(parts) == 5 {
-			rule.ResourceNames = strings.Split(parts[4], ";")
-		}
+	flag.BoolVar(&shortLived, "short-lived", false, "Issue a short-lived client certificate (SSO-style) instead of the long-lived default")
+	flag.DurationVar(&ttl, "ttl", time.Hour, "Certificate lifetime with --short-lived (and for renew) when a user's spec doesn't set ttl")
+	flag.StringVar(&intermediateCAPath, "intermediate-ca", "", "Path to a PEM intermediate CA to append to the issued cert chain")
 
-		rules = append(rules, rule)
-	}
-	return rules
+	flag.StringVar(&signerBackend, "signer", signer.BackendK8sCSR, "Signing backend: k8s-csr (default, requires CSR-approval RBAC) or local-ca (signs in-process from a Secret)")
+	flag.StringVar(&caSecretNamespace, "ca-secret-namespace", "kube-system", "Namespace of the CA Secret used by --signer=local-ca")
+	flag.StringVar(&caSecretName, "ca-secret-name", "", "Name of the CA Secret (keys tls.crt/tls.key) used by --signer=local-ca")
 }
 
-func parseBindings(bindingsStr string) []v1.Subject {
-	var subjects []v1.Subject
-	for _, bindingStr := range strings.Split(bindingsStr, ",") {
-		parts := strings.Split(bindingStr, ":")
-		if len(parts) < 2 {
-			fmt.Println("Invalid binding format")
-			continue
-		}
+// loadSpec returns the users to provision: parsed from --spec when set, or
+// else a single ad hoc user built from --username/--groups, for the
+// SSO-style "--short-lived --ttl=13h --groups sso:alice,dev" refresh flow
+// that doesn't warrant authoring a spec file.
+func loadSpec() (*userprovision.Spec, error) {
+	if specPath != "" {
+		return userprovision.LoadSpec(specPath)
+	}
+	if username == "" {
+		return nil, fmt.Errorf("--spec or --username is required")
+	}
+	return &userprovision.Spec{
+		Users: []userprovision.UserSpec{{Name: username, Groups: parseGroups(groupsFlag)}},
+	}, nil
+}
 
-		subject := v1.Subject{
-			Kind:      "User",
-			Name:      username,
-			Namespace: parts[0],
+// parseGroups splits a comma-separated --groups value into group names,
+// trimming whitespace and dropping empty entries.
+func parseGroups(groups string) []string {
+	if groups == "" {
+		return nil
+	}
+	var out []string
+	for _, g := range strings.Split(groups, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			out = append(out, g)
 		}
-		subjects = append(subjects, subject)
 	}
-	return subjects
+	return out
 }
 
-func createRole(clientset *kubernetes.Clientset, namespace string, rules []v1.PolicyRule) error {
-	role := &v1.Role{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      username + "-role",
-			Namespace: namespace,
-		},
-		Rules: rules,
+func main() {
+	args := os.Args[1:]
+	subcommand := "apply"
+	if len(args) > 0 {
+		switch args[0] {
+		case "renew", "revoke", "list", "describe":
+			subcommand = args[0]
+			args = args[1:]
+		}
 	}
+	flag.CommandLine.Parse(args)
 
-	_, err := clientset.RbacV1().Roles(namespace).Create(context.Background(), role, metav1.CreateOptions{})
-	return err
-}
-
-func createClusterRole(clientset *kubernetes.Clientset, rules []v1.PolicyRule) error {
-	clusterRole := &v1.ClusterRole{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: username + "-clusterrole",
-		},
-		Rules: rules,
+	switch subcommand {
+	case "renew":
+		runRenew(flag.Arg(0))
+		return
+	case "revoke":
+		runRevoke(flag.Arg(0))
+		return
+	case "list":
+		runList()
+		return
+	case "describe":
+		runDescribe(flag.Arg(0))
+		return
 	}
 
-	_, err := clientset.RbacV1().ClusterRoles().Create(context.Background(), clusterRole, metav1.CreateOptions{})
-	return err
+	runApply()
 }
 
-func createRoleBinding(clientset *kubernetes.Clientset, namespace, roleName string) error {
-	roleBinding := &v1.RoleBinding{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      username + "-rolebinding",
-			Namespace: namespace,
-		},
-		Subjects: []v1.Subject{
-			{
-				Kind:      "User",
-				Name:      username,
-				Namespace: namespace,
-			},
-		},
-		RoleRef: v1.RoleRef{
-			Kind:     "Role",
-			Name:     roleName,
-			APIGroup: "rbac.authorization.k8s.io",
-		},
-	}
-
-	_, err := clientset.RbacV1().RoleBindings(namespace).Create(context.Background(), roleBinding, metav1.CreateOptions{})
-	return err
+// newCRClient builds a controller-runtime client scoped to the
+// ProvisionedUser CRD, for the renew/revoke/list/describe subcommands.
+func newCRClient(cfg *rest.Config) (client.Client, error) {
+	scheme := runtime.NewScheme()
+	if err := userprovisionerv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
 }
 
-func createClusterRoleBinding(clientset *kubernetes.Clientset, clusterRoleName string) error {
-	clusterRoleBinding := &v1.ClusterRoleBinding{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: username + "-clusterrolebinding",
-		},
-		Subjects: []v1.Subject{
-			{
-				Kind:     "User",
-				Name:     username,
-				APIGroup: "rbac.authorization.k8s.io",
-			},
-		},
-		RoleRef: v1.RoleRef{
-			Kind:     "ClusterRole",
-			Name:     clusterRoleName,
-			APIGroup: "rbac.authorization.k8s.io",
-		},
-	}
-
-	_, err := clientset.RbacV1().ClusterRoleBindings().Create(context.Background(), clusterRoleBinding, metav1.CreateOptions{})
-	return err
-}
+func runRenew(username string) {
+	if username == "" {
+		fmt.Println("Error: usage: userprovisioner renew <username>")
+		os.Exit(1)
+	}
 
-func createRSAKey() (*rsa.PrivateKey, error) {
-	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	ctx := context.Background()
+	cfg, clientset, err := connect()
 	if err != nil {
-		return nil, err
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
-
-	keyFile, err := os.Create(fmt.Sprintf("%s/%s.key", dirName, username))
+	crClient, err := newCRClient(cfg)
 	if err != nil {
-		return nil, err
+		fmt.Printf("Error building CRD client: %v\n", err)
+		os.Exit(1)
 	}
-	defer keyFile.Close()
 
-	err = pem.Encode(keyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
-	if err != nil {
-		return nil, err
+	pu, err := lifecycle.Describe(ctx, crClient, username)
+	if err != nil && !apierrors.IsNotFound(err) {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	var groups, roles, clusterRoles []string
+	if pu != nil {
+		groups, roles, clusterRoles = pu.Spec.Groups, pu.Spec.Roles, pu.Spec.ClusterRoles
 	}
 
-	return key, nil
-}
+	req := userprovision.UserRequest{
+		Username:    username,
+		DirName:     fmt.Sprintf("/home/user/devtest/kubeuser/psp/%s", username),
+		ClusterName: clusterName,
+		Groups:      groups,
+		TTL:         ttl,
+	}
 
-func createCSR(key *rsa.PrivateKey) ([]byte, error) {
-	csrTemplate := x509.CertificateRequest{
-		Subject: pkix.Name{
-			CommonName: username,
-		},
+	var intermediateCA []byte
+	if intermediateCAPath != "" {
+		if intermediateCA, err = os.ReadFile(intermediateCAPath); err != nil {
+			fmt.Printf("Error reading intermediate CA: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &csrTemplate, key)
-	if err != nil {
-		return nil, err
+	provisioner := userprovision.NewProvisioner(clientset)
+	if _, err := lifecycle.Renew(ctx, crClient, provisioner, req, kubeConfigPath, intermediateCA, cfg.CAData, roles, clusterRoles); err != nil {
+		fmt.Printf("Error renewing %s: %v\n", username, err)
+		os.Exit(1)
 	}
+	fmt.Println("Successfully renewed credentials for", username)
+}
 
-	csrFile, err := os.Create(fmt.Sprintf("%s/%s.csr", dirName, username))
-	if err != nil {
-		return nil, err
+func runRevoke(username string) {
+	if username == "" {
+		fmt.Println("Error: usage: userprovisioner revoke <username>")
+		os.Exit(1)
 	}
-	defer csrFile.Close()
 
-	err = pem.Encode(csrFile, &pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes})
+	ctx := context.Background()
+	cfg, clientset, err := connect()
 	if err != nil {
-		return nil, err
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	crClient, err := newCRClient(cfg)
+	if err != nil {
+		fmt.Printf("Error building CRD client: %v\n", err)
+		os.Exit(1)
 	}
 
-	return csrBytes, nil
+	if err := lifecycle.Revoke(ctx, clientset, crClient, username); err != nil {
+		fmt.Printf("Error revoking %s: %v\n", username, err)
+		os.Exit(1)
+	}
+	fmt.Println("Successfully revoked", username)
 }
 
-func main() {
-	os.MkdirAll(dirName, os.ModePerm)
-
-	key, err := createRSAKey()
+func runList() {
+	ctx := context.Background()
+	cfg, _, err := connect()
 	if err != nil {
-		fmt.Printf("Error generating RSA key: %v\n", err)
-		return
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
-	fmt.Println("Successfully generated RSA key...")
-
-	csrBytes, err := createCSR(key)
+	crClient, err := newCRClient(cfg)
 	if err != nil {
-		fmt.Printf("Error generating CSR: %v\n", err)
-		return
+		fmt.Printf("Error building CRD client: %v\n", err)
+		os.Exit(1)
 	}
-	fmt.Println("Successfully generated CSR...")
 
-	cfg, err := config.GetConfig()
+	list, err := lifecycle.List(ctx, crClient)
 	if err != nil {
-		fmt.Printf("Error getting kubeconfig: %v\n", err)
-		return
+		fmt.Printf("Error listing users: %v\n", err)
+		os.Exit(1)
 	}
 
-	clientset, err := kubernetes.NewForConfig(cfg)
-	if err != nil {
-		fmt.Printf("Error creating Kubernetes client: %v\n", err)
-		return
+	fmt.Printf("%-20s %-25s %s\n", "USERNAME", "NOT AFTER", "READY")
+	for _, pu := range list.Items {
+		ready := "Unknown"
+		for _, c := range pu.Status.Conditions {
+			if c.Type == "Ready" {
+				ready = string(c.Status)
+			}
+		}
+		fmt.Printf("%-20s %-25s %s\n", pu.Spec.Username, pu.Status.NotAfter.Format(time.RFC3339), ready)
 	}
+}
 
-	csrName := username
-	encodedCSR := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes})
-	csr := &certificatesv1.CertificateSigningRequest{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: csrName,
-		},
-		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Request:    encodedCSR,
-			Usages:     []certificatesv1.KeyUsage{certificatesv1.UsageClientAuth},
-			Expiry:     &metav1.Duration{Duration: userExpirationSec * time.Second},
-			SignerName: "kubernetes.io/kube-apiserver-client",
-		},
+func runDescribe(username string) {
+	if username == "" {
+		fmt.Println("Error: usage: userprovisioner describe <username>")
+		os.Exit(1)
 	}
 
-	_, err = clientset.CertificatesV1().CertificateSigningRequests().Create(context.Background(), csr, metav1.CreateOptions{})
+	ctx := context.Background()
+	cfg, _, err := connect()
 	if err != nil {
-		fmt.Printf("Error creating Kubernetes CSR: %v\n", err)
-		return
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	crClient, err := newCRClient(cfg)
+	if err != nil {
+		fmt.Printf("Error building CRD client: %v\n", err)
+		os.Exit(1)
 	}
-	fmt.Println("Successfully applied Kubernetes CSR...")
 
-	// Approve the CSR
-	csr, err = clientset.CertificatesV1().CertificateSigningRequests().Get(context.Background(), csrName, metav1.GetOptions{})
+	pu, err := lifecycle.Describe(ctx, crClient, username)
 	if err != nil {
-		fmt.Printf("Error getting Kubernetes CSR: %v\n", err)
-		return
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
-		Type:    certificatesv1.CertificateApproved,
-		Status:  corev1.ConditionTrue,
-		Reason:  "KubectlCreateUser",
-		Message: "Approved by kubectl-create-user plugin",
-	})
+	fmt.Printf("Username:     %s\n", pu.Spec.Username)
+	fmt.Printf("Groups:       %v\n", pu.Spec.Groups)
+	fmt.Printf("Roles:        %v\n", pu.Spec.Roles)
+	fmt.Printf("ClusterRoles: %v\n", pu.Spec.ClusterRoles)
+	fmt.Printf("Issued At:    %s\n", pu.Status.IssuedAt.Format(time.RFC3339))
+	fmt.Printf("Not After:    %s\n", pu.Status.NotAfter.Format(time.RFC3339))
+	fmt.Printf("CSR Name:     %s\n", pu.Status.CSRName)
+	fmt.Printf("Fingerprint:  %s\n", pu.Status.CertFingerprint)
+	for _, c := range pu.Status.Conditions {
+		fmt.Printf("Condition:    %s=%s (%s) %s\n", c.Type, c.Status, c.Reason, c.Message)
+	}
+}
 
-	_, err = clientset.CertificatesV1().CertificateSigningRequests().UpdateApproval(context.Background(), csrName, csr, metav1.UpdateOptions{})
+// connect builds the Kubernetes rest.Config and a clientset shared by every
+// subcommand.
+func connect() (*rest.Config, *kubernetes.Clientset, error) {
+	cfg, err := config.GetConfig()
 	if err != nil {
-		fmt.Printf("Error approving Kubernetes CSR: %v\n", err)
-		return
+		return nil, nil, fmt.Errorf("getting kubeconfig: %w", err)
 	}
-	fmt.Println("Successfully approved Kubernetes CSR...")
-
-	// Fetch the signed certificate
-	csr, err = clientset.CertificatesV1().CertificateSigningRequests().Get(context.Background(), csrName, metav1.GetOptions{})
+	clientset, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
-		fmt.Printf("Error getting Kubernetes CSR: %v\n", err)
-		return
+		return nil, nil, fmt.Errorf("creating Kubernetes client: %w", err)
 	}
+	return cfg, clientset, nil
+}
 
-	crtBytes := csr.Status.Certificate
-	crtFile, err := os.Create(fmt.Sprintf("%s/%s.crt", dirName, username))
+func runApply() {
+	spec, err := loadSpec()
 	if err != nil {
-		fmt.Printf("Error creating certificate file: %v\n", err)
-		return
+		fmt.Printf("Error loading spec: %v\n", err)
+		os.Exit(1)
 	}
-	defer crtFile.Close()
 
-	_, err = crtFile.Write(crtBytes)
-	if err != nil {
-		fmt.Printf("Error writing certificate file: %v\n", err)
+	if dryRun {
+		for _, user := range spec.Users {
+			doc, err := userprovision.RenderRBACYAML(user)
+			if err != nil {
+				fmt.Printf("Error rendering YAML for %s: %v\n", user.Name, err)
+				os.Exit(1)
+			}
+			fmt.Print(doc)
+		}
 		return
 	}
-	fmt.Println("Successfully created certificate file...")
 
-	// Configure kubeconfig for the user
-	kubeconfig, err := os.ReadFile(kubeConfigPath)
+	cfg, clientset, err := connect()
 	if err != nil {
-		fmt.Printf("Error reading kubeconfig: %v\n", err)
-		return
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
 	}
-
-	kubeconfigPath := fmt.Sprintf("%s/%s-config", dirName, username)
-	err = ioutil.WriteFile(kubeconfigPath, kubeconfig, 0644)
+	crClient, err := newCRClient(cfg)
 	if err != nil {
-		fmt.Printf("Error writing user kubeconfig: %v\n", err)
-		return
+		fmt.Printf("Error building CRD client: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Update kubeconfig with user credentials
-	cmd := exec.Command("kubectl", "--kubeconfig", kubeconfigPath, "config", "set-credentials", username,
-		"--client-key", fmt.Sprintf("%s/%s.key", dirName, username),
-		"--client-certificate", fmt.Sprintf("%s/%s.crt", dirName, username),
-		"--embed-certs=true")
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Error setting credentials in kubeconfig: %v\n", err)
-		return
+	var intermediateCA []byte
+	if intermediateCAPath != "" {
+		intermediateCA, err = os.ReadFile(intermediateCAPath)
+		if err != nil {
+			fmt.Printf("Error reading intermediate CA: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	// Set context for the user
-	cmd = exec.Command("kubectl", "--kubeconfig", kubeconfigPath, "config", "set-context", username,
-		"--cluster", clusterName,
-		"--user", username)
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Error setting context in kubeconfig: %v\n", err)
-		return
-	}
+	ctx := context.Background()
 
-	cmd = exec.Command("kubectl", "--kubeconfig", kubeconfigPath, "config", "use-context", username)
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Error using context in kubeconfig: %v\n", err)
-		return
-	}
+	for _, user := range spec.Users {
+		creds, err := provisionUser(ctx, clientset, cfg.CAData, intermediateCA, user)
+		if err != nil {
+			fmt.Printf("Error provisioning %s: %v\n", user.Name, err)
+			os.Exit(1)
+		}
 
-	fmt.Println("Congratulations... the user", username, "created successfully...")
+		if err := userprovision.ApplyRBAC(ctx, clientset, user); err != nil {
+			fmt.Printf("Error applying RBAC for %s: %v\n", user.Name, err)
+			os.Exit(1)
+		}
 
-	// Create Role, ClusterRole, RoleBinding, and ClusterRoleBinding if specified
-	if roleRules != "" {
-		rules := parseRules(roleRules)
-		for _, rule := range rules {
-			if err := createRole(clientset, rule.Namespace, rule.Rules); err != nil {
-				fmt.Printf("Error creating Role: %v\n", err)
-				return
+		if prune {
+			if err := userprovision.Prune(ctx, clientset, user); err != nil {
+				fmt.Printf("Error pruning for %s: %v\n", user.Name, err)
+				os.Exit(1)
 			}
 		}
-		fmt.Println("Successfully created Roles...")
-	}
 
-	if clusterRoleRules != "" {
-		rules := parseRules(clusterRoleRules)
-		for _, rule := range rules {
-			if err := createClusterRole(clientset, rule.Rules); err != nil {
-				fmt.Printf("Error creating ClusterRole: %v\n", err)
-				return
-			}
+		recordReq := userprovision.UserRequest{Username: user.Name, Groups: user.Groups}
+		if err := lifecycle.Record(ctx, crClient, recordReq, creds, user.RoleNames(), user.ClusterRoleNames()); err != nil {
+			fmt.Printf("Error recording ProvisionedUser for %s: %v\n", user.Name, err)
+			os.Exit(1)
 		}
-		fmt.Println("Successfully created ClusterRoles...")
+
+		fmt.Println("Congratulations... the user", user.Name, "created successfully...")
 	}
+}
 
-	if roleBindings != "" {
-		bindings := parseBindings(roleBindings)
-		for _, binding := range bindings {
-			if err := createRoleBinding(clientset, binding.Namespace, binding.RoleName); err != nil {
-				fmt.Printf("Error creating RoleBinding: %v\n", err)
-				return
-			}
-		}
-		fmt.Println("Successfully created RoleBindings...")
+func provisionUser(ctx context.Context, clientset *kubernetes.Clientset, apiServerCA, intermediateCA []byte, user userprovision.UserSpec) (*userprovision.IssuedCredentials, error) {
+	userTTL, err := user.ParsedTTL()
+	if err != nil {
+		return nil, err
 	}
 
-	if clusterRoleBindings != "" {
-		bindings := parseBindings(clusterRoleBindings)
-		for _, binding := range bindings {
-			if err := createClusterRoleBinding(clientset, binding.RoleName); err != nil {
-				fmt.Printf("Error creating ClusterRoleBinding: %v\n", err)
-				return
-			}
+	req := userprovision.UserRequest{
+		Username:    user.Name,
+		DirName:     fmt.Sprintf("/home/user/devtest/kubeuser/psp/%s", user.Name),
+		ClusterName: clusterName,
+		Groups:      user.Groups,
+	}
+	switch {
+	case userTTL > 0:
+		req.TTL = userTTL
+	case shortLived:
+		req.TTL = ttl
+	default:
+		req.Expiration = 315569520 * time.Second
+	}
+
+	provisioner := userprovision.NewProvisioner(clientset)
+	switch signerBackend {
+	case signer.BackendK8sCSR:
+		// provisioner.Signer stays nil; Provision falls back to the default.
+	case signer.BackendLocalCA:
+		if caSecretName == "" {
+			return nil, fmt.Errorf("--ca-secret-name is required when --signer=local-ca")
+		}
+		expiry := req.Expiration
+		if req.TTL > 0 {
+			expiry = req.TTL
 		}
-		fmt.Println("Successfully created ClusterRoleBindings...")
+		localSigner, err := signer.NewLocalCASigner(ctx, clientset, signer.SecretRef{
+			Namespace: caSecretNamespace,
+			Name:      caSecretName,
+		}, user.Name, expiry)
+		if err != nil {
+			return nil, fmt.Errorf("building local-ca signer: %w", err)
+		}
+		provisioner.Signer = localSigner
+	default:
+		return nil, fmt.Errorf("unknown --signer %q, want %q or %q", signerBackend, signer.BackendK8sCSR, signer.BackendLocalCA)
 	}
+
+	return provisioner.Provision(ctx, req, kubeConfigPath, intermediateCA, apiServerCA)
 }
@@ -0,0 +1,64 @@
+// Package v1alpha1 contains the ProvisionedUser API, group
+// userprovisioner.k8s, version v1alpha1: a record of a user whose
+// credentials and RBAC grants were issued by this tool, so access can be
+// audited, renewed, and revoked as a managed lifecycle instead of a
+// write-and-forget filesystem artifact.
+// +kubebuilder:object:generate=true
+// +groupName=userprovisioner.k8s
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProvisionedUserSpec is the desired state recorded at issuance time.
+type ProvisionedUserSpec struct {
+	// Username is the CSR CommonName / kubeconfig user this record tracks.
+	Username string `json:"username"`
+	// Groups are the CSR Subject Organization values granted to the user.
+	Groups []string `json:"groups,omitempty"`
+	// Roles lists the "<username>-role"-style Role names bound to the user.
+	Roles []string `json:"roles,omitempty"`
+	// ClusterRoles lists the ClusterRole names bound to the user.
+	ClusterRoles []string `json:"clusterRoles,omitempty"`
+}
+
+// ProvisionedUserStatus is the observed state of an issued credential.
+type ProvisionedUserStatus struct {
+	// IssuedAt is when the current certificate was signed.
+	IssuedAt metav1.Time `json:"issuedAt,omitempty"`
+	// NotAfter is the current certificate's expiry.
+	NotAfter metav1.Time `json:"notAfter,omitempty"`
+	// CSRName is the CertificateSigningRequest object this credential came from.
+	CSRName string `json:"csrName,omitempty"`
+	// CertFingerprint is the SHA-256 fingerprint of the current certificate,
+	// used to detect renewal and to populate the CRL on revoke.
+	CertFingerprint string `json:"certFingerprint,omitempty"`
+	// Conditions track issuance/revocation state: "Ready" (set on issuance
+	// and renewal) and "Revoked".
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// ProvisionedUser tracks a user whose credentials were issued by
+// userprovisioner, so access can be listed, described, renewed, and revoked.
+type ProvisionedUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProvisionedUserSpec   `json:"spec,omitempty"`
+	Status ProvisionedUserStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ProvisionedUserList is a list of ProvisionedUser.
+type ProvisionedUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ProvisionedUser `json:"items"`
+}
@@ -0,0 +1,168 @@
+// Package csrapprover implements a long-lived controller that watches
+// CertificateSigningRequests and auto-approves the ones this tool issued,
+// so the CSR-issue/approve/fetch loop that main() runs once can also run
+// continuously inside the cluster.
+package csrapprover
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AutoApproveAnnotation marks a CSR as eligible for auto-approval. CSRs
+// matching SignerName/UsernamePrefix are also eligible without it.
+const AutoApproveAnnotation = "userprovisioner.k8s/auto-approve"
+
+// AllowListConfigMapKey is the key inside the allow-list ConfigMap holding a
+// newline-separated list of "commonName:group1,group2" allowed identities.
+const AllowListConfigMapKey = "allowlist"
+
+// MinKeySizeBits is the minimum public key size, in bits, this controller
+// will approve. It is applied to RSA and ECDSA keys alike, so ECDSA keys
+// (at most 521 bits) are always denied.
+const MinKeySizeBits = 2048
+
+// Denial reason codes recorded on csrDeniedTotal. These must stay a small
+// fixed set — the detailed, CommonName/duration-bearing message goes on the
+// Denied Event and the Status condition instead, never on the metric label.
+const (
+	ReasonParseError     = "parse-error"
+	ReasonKeyTooSmall    = "key-too-small"
+	ReasonExpiryTooLong  = "expiry-too-long"
+	ReasonNotAllowListed = "not-allow-listed"
+	ReasonAllowListError = "allow-list-error"
+)
+
+// Reconciler approves or denies CertificateSigningRequests carrying
+// AutoApproveAnnotation or matching SignerName and UsernamePrefix.
+type Reconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+
+	// SignerName, alongside UsernamePrefix, is an alternate match rule to
+	// AutoApproveAnnotation for CSRs that should be considered.
+	SignerName     string
+	UsernamePrefix string
+
+	// MaxExpiry caps the requested certificate lifetime; CSRs asking for
+	// longer are denied.
+	MaxExpiry time.Duration
+
+	// AllowListConfigMap names a ConfigMap (namespace and name) whose
+	// AllowListConfigMapKey entry enumerates the
+	// CommonName/Organization combinations this controller may approve.
+	AllowListConfigMap types.NamespacedName
+}
+
+// SetupWithManager registers the Reconciler to watch CertificateSigningRequests.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&certificatesv1.CertificateSigningRequest{}).
+		Complete(r)
+}
+
+// Reconcile implements reconcile.Reconciler.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	csr := &certificatesv1.CertificateSigningRequest{}
+	if err := r.Get(ctx, req.NamespacedName, csr); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if isDecided(csr) || !r.eligible(csr) {
+		return ctrl.Result{}, nil
+	}
+
+	waitStart := csr.CreationTimestamp.Time
+
+	if reasonCode, message := r.validate(ctx, csr); reasonCode != "" {
+		csrDeniedTotal.WithLabelValues(reasonCode).Inc()
+		csrWaitSeconds.Observe(time.Since(waitStart).Seconds())
+		r.Recorder.Eventf(csr, corev1.EventTypeWarning, "Denied", "denying CSR %s: %s", csr.Name, message)
+		return ctrl.Result{}, r.decide(ctx, csr, certificatesv1.CertificateDenied, "AutoDenied", message)
+	}
+
+	csrApprovedTotal.Inc()
+	csrWaitSeconds.Observe(time.Since(waitStart).Seconds())
+	r.Recorder.Eventf(csr, corev1.EventTypeNormal, "Approved", "approving CSR %s", csr.Name)
+	return ctrl.Result{}, r.decide(ctx, csr, certificatesv1.CertificateApproved, "AutoApproved", "approved by userprovisioner csrapprover controller")
+}
+
+func isDecided(csr *certificatesv1.CertificateSigningRequest) bool {
+	for _, c := range csr.Status.Conditions {
+		if c.Type == certificatesv1.CertificateApproved || c.Type == certificatesv1.CertificateDenied {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reconciler) eligible(csr *certificatesv1.CertificateSigningRequest) bool {
+	if csr.Annotations[AutoApproveAnnotation] == "true" {
+		return true
+	}
+	if r.SignerName != "" && csr.Spec.SignerName == r.SignerName &&
+		r.UsernamePrefix != "" && len(csr.Spec.Username) >= len(r.UsernamePrefix) &&
+		csr.Spec.Username[:len(r.UsernamePrefix)] == r.UsernamePrefix {
+		return true
+	}
+	return false
+}
+
+// validate returns a non-empty denial reason code plus a detailed message, or
+// two empty strings if the CSR is acceptable. The reason code is the only
+// part that may be used as a metric label; the message may embed CSR-specific
+// detail (CommonName, durations, parse errors) and belongs on the Denied
+// Event/Status condition only.
+func (r *Reconciler) validate(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (reasonCode, message string) {
+	parsed, err := parseCSR(csr.Spec.Request)
+	if err != nil {
+		return ReasonParseError, fmt.Sprintf("parsing CSR: %v", err)
+	}
+
+	bits, ok := keyBits(parsed)
+	if !ok {
+		return ReasonKeyTooSmall, fmt.Sprintf("unsupported public key type %T", parsed.PublicKey)
+	}
+	if bits < MinKeySizeBits {
+		return ReasonKeyTooSmall, fmt.Sprintf("key size %d bits is below the minimum of %d", bits, MinKeySizeBits)
+	}
+
+	if csr.Spec.ExpirationSeconds != nil {
+		requested := time.Duration(*csr.Spec.ExpirationSeconds) * time.Second
+		if requested > r.MaxExpiry {
+			return ReasonExpiryTooLong, fmt.Sprintf("requested expiry %s exceeds max %s", requested, r.MaxExpiry)
+		}
+	}
+
+	if r.AllowListConfigMap.Name != "" {
+		allowed, err := r.allowListAllows(ctx, parsed.Subject.CommonName, parsed.Subject.Organization)
+		if err != nil {
+			return ReasonAllowListError, fmt.Sprintf("checking allow-list: %v", err)
+		}
+		if !allowed {
+			return ReasonNotAllowListed, fmt.Sprintf("%s (groups %v) is not on the allow-list", parsed.Subject.CommonName, parsed.Subject.Organization)
+		}
+	}
+
+	return "", ""
+}
+
+func (r *Reconciler) decide(ctx context.Context, csr *certificatesv1.CertificateSigningRequest, condType certificatesv1.RequestConditionType, reason, message string) error {
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:    condType,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	// Approved/Denied conditions are only accepted through the "approval"
+	// subresource; the status subresource preserves the old ones.
+	return r.SubResource("approval").Update(ctx, csr)
+}
@@ -0,0 +1,29 @@
+package csrapprover
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	csrApprovedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "csr_approved_total",
+		Help: "Total number of CertificateSigningRequests approved by the controller.",
+	})
+
+	csrDeniedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "csr_denied_total",
+		Help: "Total number of CertificateSigningRequests denied by the controller, by reason.",
+	}, []string{"reason"})
+
+	csrWaitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "csr_wait_seconds",
+		Help:    "Seconds between CSR creation and the controller's approve/deny decision.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(csrApprovedTotal, csrDeniedTotal, csrWaitSeconds)
+}
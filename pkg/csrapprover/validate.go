@@ -0,0 +1,79 @@
+package csrapprover
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func parseCSR(csrPEM []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in CSR")
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
+
+// keyBits returns the CSR's public key size in bits for key types this
+// controller knows how to size (RSA and ECDSA). ok is false for any other
+// key type, so callers don't mistake "couldn't size it" for "big enough".
+func keyBits(csr *x509.CertificateRequest) (bits int, ok bool) {
+	switch key := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen(), true
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize, true
+	default:
+		return 0, false
+	}
+}
+
+// allowListAllows reads r.AllowListConfigMap and reports whether
+// "commonName:group1,group2,..." lines permit commonName with groups.
+// A CSR is allowed if its CommonName appears with no groups listed (any
+// groups OK) or with a groups list that is a superset of the CSR's groups.
+func (r *Reconciler) allowListAllows(ctx context.Context, commonName string, groups []string) (bool, error) {
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: r.AllowListConfigMap.Namespace, Name: r.AllowListConfigMap.Name}, cm); err != nil {
+		return false, err
+	}
+
+	for _, line := range strings.Split(cm.Data[AllowListConfigMapKey], "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if parts[0] != commonName {
+			continue
+		}
+		if len(parts) == 1 || parts[1] == "" {
+			return true, nil
+		}
+		allowedGroups := strings.Split(parts[1], ",")
+		if containsAll(allowedGroups, groups) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func containsAll(superset, subset []string) bool {
+	allowed := make(map[string]bool, len(superset))
+	for _, g := range superset {
+		allowed[g] = true
+	}
+	for _, g := range subset {
+		if !allowed[g] {
+			return false
+		}
+	}
+	return true
+}
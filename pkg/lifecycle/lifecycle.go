@@ -0,0 +1,257 @@
+// Package lifecycle implements the renew/revoke/list/describe operations
+// that turn ProvisionedUser records into a managed credential lifecycle,
+// instead of the original one-shot "write cert to disk and forget" flow.
+package lifecycle
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userprovisionerv1alpha1 "github.com/hamido95/go-kubernetes-user-creator/pkg/apis/userprovisioner/v1alpha1"
+	"github.com/hamido95/go-kubernetes-user-creator/pkg/userprovision"
+)
+
+// CRLConfigMap names the cluster-stored ConfigMap a webhook or sidecar can
+// consume as a certificate revocation list.
+var CRLConfigMap = types.NamespacedName{Namespace: "kube-system", Name: "userprovisioner-crl"}
+
+// CRLDataKey is the key inside CRLConfigMap holding a newline-separated list
+// of the SHA-256 fingerprints (ProvisionedUser Status.CertFingerprint) of
+// revoked certificates. Certificate serial numbers are not recorded.
+const CRLDataKey = "revoked-serials"
+
+// Renew generates a new key+CSR for username and signs it via provisioner.
+// The k8s-csr signer backend names its CertificateSigningRequest object
+// after the username, so Renew first deletes any CertificateSigningRequest
+// left over from the previous issuance: otherwise provisioner.Provision's
+// Create hits AlreadyExists and the signer's reuseIfValid hands back the
+// still-unexpired *old* certificate, which was issued for the old private
+// key, not the new one Renew just generated — a client-key/client-certificate
+// mismatch that breaks TLS client auth. provisioner.Provision builds the new
+// kubeconfig via userprovision.BuildKubeconfig, which writes to a temporary
+// path and renames it over the live per-user kubeconfig only once fully
+// assembled, so a failure partway through a renewal leaves the previous,
+// still-valid kubeconfig in place. roles and clusterRoles are the RBAC
+// grants already recorded for username (Renew doesn't re-run RBAC
+// application, so it carries them forward into the updated ProvisionedUser
+// record).
+func Renew(ctx context.Context, crClient client.Client, provisioner *userprovision.Provisioner, req userprovision.UserRequest, baseKubeconfigPath string, intermediateCA, apiServerCA []byte, roles, clusterRoles []string) (*userprovision.IssuedCredentials, error) {
+	if err := deleteExistingCSR(ctx, provisioner.Clientset, req.Username); err != nil {
+		return nil, fmt.Errorf("clearing previous CSR for %s: %w", req.Username, err)
+	}
+
+	creds, err := provisioner.Provision(ctx, req, baseKubeconfigPath, intermediateCA, apiServerCA)
+	if err != nil {
+		return nil, fmt.Errorf("renewing credentials for %s: %w", req.Username, err)
+	}
+
+	if err := Record(ctx, crClient, req, creds, roles, clusterRoles); err != nil {
+		return nil, fmt.Errorf("recording renewal for %s: %w", req.Username, err)
+	}
+
+	return creds, nil
+}
+
+// deleteExistingCSR deletes the CertificateSigningRequest named username, if
+// any, so a subsequent Create by the k8s-csr signer backend doesn't hit
+// AlreadyExists and reuse a certificate bound to a different (old) private
+// key. It's a no-op (and harmless) for the local-ca backend, which never
+// creates a CertificateSigningRequest object.
+func deleteExistingCSR(ctx context.Context, clientset *kubernetes.Clientset, username string) error {
+	err := clientset.CertificatesV1().CertificateSigningRequests().Delete(ctx, username, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Revoke deletes username's RoleBindings/ClusterRoleBindings, deletes the
+// CSR object, and adds the current certificate's SHA-256 fingerprint (not
+// its serial number) to the CRL ConfigMap, then marks the ProvisionedUser as
+// revoked.
+func Revoke(ctx context.Context, clientset *kubernetes.Clientset, crClient client.Client, username string) error {
+	pu := &userprovisionerv1alpha1.ProvisionedUser{}
+	if err := crClient.Get(ctx, types.NamespacedName{Name: username}, pu); err != nil {
+		return fmt.Errorf("getting ProvisionedUser %s: %w", username, err)
+	}
+
+	if err := deleteOwnedRoleBindings(ctx, clientset, username); err != nil {
+		return fmt.Errorf("deleting role bindings for %s: %w", username, err)
+	}
+	if err := deleteOwnedClusterRoleBindings(ctx, clientset, username); err != nil {
+		return fmt.Errorf("deleting cluster role bindings for %s: %w", username, err)
+	}
+
+	if err := clientset.CertificatesV1().CertificateSigningRequests().Delete(ctx, pu.Status.CSRName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("deleting CSR %s: %w", pu.Status.CSRName, err)
+	}
+
+	if serial := pu.Status.CertFingerprint; serial != "" {
+		if err := addToCRL(ctx, clientset, serial); err != nil {
+			return fmt.Errorf("updating CRL: %w", err)
+		}
+	}
+
+	pu.Status.Conditions = append(pu.Status.Conditions, metav1.Condition{
+		Type:               "Revoked",
+		Status:             metav1.ConditionTrue,
+		Reason:             "Revoke",
+		Message:            "revoked via userprovisioner revoke",
+		LastTransitionTime: metav1.Now(),
+	})
+	return crClient.Status().Update(ctx, pu)
+}
+
+// List returns every tracked ProvisionedUser, for auditing who currently has
+// access.
+func List(ctx context.Context, crClient client.Client) (*userprovisionerv1alpha1.ProvisionedUserList, error) {
+	list := &userprovisionerv1alpha1.ProvisionedUserList{}
+	if err := crClient.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("listing ProvisionedUsers: %w", err)
+	}
+	return list, nil
+}
+
+// Describe returns the full ProvisionedUser record for username.
+func Describe(ctx context.Context, crClient client.Client, username string) (*userprovisionerv1alpha1.ProvisionedUser, error) {
+	pu := &userprovisionerv1alpha1.ProvisionedUser{}
+	if err := crClient.Get(ctx, types.NamespacedName{Name: username}, pu); err != nil {
+		return nil, fmt.Errorf("getting ProvisionedUser %s: %w", username, err)
+	}
+	return pu, nil
+}
+
+// Record upserts the ProvisionedUser CR for req.Username: it creates the CR
+// the first time this tool provisions a user, and on every call (including
+// re-applying an unchanged --spec, and renewal) refreshes the tracked Spec
+// (Groups/Roles/ClusterRoles) and Status (issuance time, expiry,
+// fingerprint) to match the credentials and RBAC grants just applied.
+func Record(ctx context.Context, crClient client.Client, req userprovision.UserRequest, creds *userprovision.IssuedCredentials, roles, clusterRoles []string) error {
+	fingerprint, notAfter, err := certFingerprintAndExpiry(creds.CertPEM)
+	if err != nil {
+		return err
+	}
+
+	pu := &userprovisionerv1alpha1.ProvisionedUser{}
+	err = crClient.Get(ctx, types.NamespacedName{Name: req.Username}, pu)
+	if apierrors.IsNotFound(err) {
+		pu = &userprovisionerv1alpha1.ProvisionedUser{ObjectMeta: metav1.ObjectMeta{Name: req.Username}}
+		if err := crClient.Create(ctx, pu); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	pu.Spec = userprovisionerv1alpha1.ProvisionedUserSpec{
+		Username:     req.Username,
+		Groups:       req.Groups,
+		Roles:        roles,
+		ClusterRoles: clusterRoles,
+	}
+	if err := crClient.Update(ctx, pu); err != nil {
+		return fmt.Errorf("updating ProvisionedUser spec for %s: %w", req.Username, err)
+	}
+
+	pu.Status = userprovisionerv1alpha1.ProvisionedUserStatus{
+		IssuedAt:        metav1.Now(),
+		NotAfter:        metav1.NewTime(notAfter),
+		CSRName:         req.Username,
+		CertFingerprint: fingerprint,
+		Conditions: []metav1.Condition{{
+			Type:               "Ready",
+			Status:             metav1.ConditionTrue,
+			Reason:             "Issued",
+			Message:            "certificate issued",
+			LastTransitionTime: metav1.Now(),
+		}},
+	}
+	return crClient.Status().Update(ctx, pu)
+}
+
+func certFingerprintAndExpiry(certPEM []byte) (fingerprint string, notAfter time.Time, err error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return "", time.Time{}, fmt.Errorf("no PEM data found in certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("parsing certificate: %w", err)
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return fmt.Sprintf("%x", sum), cert.NotAfter, nil
+}
+
+func deleteOwnedRoleBindings(ctx context.Context, clientset *kubernetes.Clientset, username string) error {
+	selector := fmt.Sprintf("%s=%s", userprovision.OwnerLabelKey, username)
+	bindings, err := clientset.RbacV1().RoleBindings("").List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return err
+	}
+	for _, rb := range bindings.Items {
+		if err := clientset.RbacV1().RoleBindings(rb.Namespace).Delete(ctx, rb.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteOwnedClusterRoleBindings deletes every ClusterRoleBinding labeled
+// userprovisioner.k8s/owner=username, mirroring deleteOwnedRoleBindings.
+// A single hardcoded "<username>-clusterrolebinding" name isn't enough: a
+// user may have a named ClusterRoleBindingSpec (see
+// ClusterRoleBindingSpec.ObjectName) or more than one binding, and every one
+// of them grants cluster-wide access that Revoke must remove.
+func deleteOwnedClusterRoleBindings(ctx context.Context, clientset *kubernetes.Clientset, username string) error {
+	selector := fmt.Sprintf("%s=%s", userprovision.OwnerLabelKey, username)
+	bindings, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return err
+	}
+	for _, crb := range bindings.Items {
+		if err := clientset.RbacV1().ClusterRoleBindings().Delete(ctx, crb.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func addToCRL(ctx context.Context, clientset *kubernetes.Clientset, serial string) error {
+	cm, err := clientset.CoreV1().ConfigMaps(CRLConfigMap.Namespace).Get(ctx, CRLConfigMap.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: CRLConfigMap.Name, Namespace: CRLConfigMap.Namespace},
+			Data:       map[string]string{CRLDataKey: serial},
+		}
+		_, err := clientset.CoreV1().ConfigMaps(CRLConfigMap.Namespace).Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	existing := strings.Split(cm.Data[CRLDataKey], "\n")
+	for _, s := range existing {
+		if s == serial {
+			return nil
+		}
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[CRLDataKey] = strings.Trim(cm.Data[CRLDataKey]+"\n"+serial, "\n")
+	_, err = clientset.CoreV1().ConfigMaps(CRLConfigMap.Namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
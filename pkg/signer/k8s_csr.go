@@ -0,0 +1,135 @@
+package signer
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// certificateWaitTimeout bounds how long Sign waits for kube-controller-manager
+// to populate Status.Certificate after approval.
+const certificateWaitTimeout = 2 * time.Minute
+
+// K8sCSRSigner drives the certificates.k8s.io CSR, approve, and fetch flow.
+// It requires cluster-admin (or an equivalent certificatesigningrequests
+// approval) permission.
+type K8sCSRSigner struct {
+	Clientset  *kubernetes.Clientset
+	Name       string
+	SignerName string
+}
+
+// NewK8sCSRSigner returns a Signer that submits and approves a
+// CertificateSigningRequest named name against the cluster.
+func NewK8sCSRSigner(clientset *kubernetes.Clientset, name, signerName string) *K8sCSRSigner {
+	if signerName == "" {
+		signerName = "kubernetes.io/kube-apiserver-client"
+	}
+	return &K8sCSRSigner{Clientset: clientset, Name: name, SignerName: signerName}
+}
+
+// Sign implements Signer.
+func (s *K8sCSRSigner) Sign(ctx context.Context, csrPEM []byte, notAfter time.Time) ([]byte, error) {
+	expirationSeconds := int32(time.Until(notAfter).Round(time.Second).Seconds())
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: s.Name,
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:           csrPEM,
+			Usages:            []certificatesv1.KeyUsage{certificatesv1.UsageClientAuth},
+			ExpirationSeconds: &expirationSeconds,
+			SignerName:        s.SignerName,
+		},
+	}
+
+	if _, err := s.Clientset.CertificatesV1().CertificateSigningRequests().Create(ctx, csr, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("creating Kubernetes CSR: %w", err)
+		}
+		if certPEM, reusable := s.reuseIfValid(ctx); reusable {
+			return certPEM, nil
+		}
+	}
+
+	csr, err := s.Clientset.CertificatesV1().CertificateSigningRequests().Get(ctx, s.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("getting Kubernetes CSR: %w", err)
+	}
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:    certificatesv1.CertificateApproved,
+		Status:  corev1.ConditionTrue,
+		Reason:  "KubectlCreateUser",
+		Message: "Approved by kubectl-create-user plugin",
+	})
+
+	if _, err := s.Clientset.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, s.Name, csr, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("approving Kubernetes CSR: %w", err)
+	}
+
+	return s.waitForCertificate(ctx)
+}
+
+// waitForCertificate polls with backoff for the signer (kube-controller-manager
+// or an external signer controller) to populate Status.Certificate, since it
+// does so asynchronously after approval rather than inline with UpdateApproval.
+func (s *K8sCSRSigner) waitForCertificate(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, certificateWaitTimeout)
+	defer cancel()
+
+	var certPEM []byte
+	backoff := wait.Backoff{
+		Duration: 500 * time.Millisecond,
+		Factor:   2,
+		Steps:    8,
+		Cap:      15 * time.Second,
+	}
+
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		csr, err := s.Clientset.CertificatesV1().CertificateSigningRequests().Get(ctx, s.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if len(csr.Status.Certificate) == 0 {
+			return false, nil
+		}
+		certPEM = csr.Status.Certificate
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("timed out waiting for CSR %s to be signed: %w", s.Name, err)
+	}
+
+	return certPEM, nil
+}
+
+// reuseIfValid returns the existing CSR's signed certificate when it is
+// present and not yet expired, so re-running against an already-issued CSR
+// doesn't error out on AlreadyExists.
+func (s *K8sCSRSigner) reuseIfValid(ctx context.Context) ([]byte, bool) {
+	existing, err := s.Clientset.CertificatesV1().CertificateSigningRequests().Get(ctx, s.Name, metav1.GetOptions{})
+	if err != nil || len(existing.Status.Certificate) == 0 {
+		return nil, false
+	}
+
+	block, _ := pem.Decode(existing.Status.Certificate)
+	if block == nil {
+		return nil, false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil || time.Now().After(cert.NotAfter) {
+		return nil, false
+	}
+
+	return existing.Status.Certificate, true
+}
@@ -0,0 +1,141 @@
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	cfsslconfig "github.com/cloudflare/cfssl/config"
+	"github.com/cloudflare/cfssl/helpers"
+	cfsslsigner "github.com/cloudflare/cfssl/signer"
+	"github.com/cloudflare/cfssl/signer/local"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LocalCASigner signs CSRs in-process using a CA cert+key pulled from a
+// Kubernetes Secret, via cfssl's local.Signer. It doesn't touch
+// certificates.k8s.io, so it doesn't require CSR-approval RBAC, and it
+// works with intermediate CAs kube-apiserver-client doesn't trust.
+//
+// Sign returns only the leaf certificate; chaining it to an intermediate CA
+// is BuildKubeconfig's job (shared with every other Signer backend), not
+// this one's.
+type LocalCASigner struct {
+	// Username is the expected CSR CommonName; CSRs for any other name are
+	// refused.
+	Username string
+
+	signer *local.Signer
+}
+
+// SecretRef names the Kubernetes Secret a local-ca signer loads its CA
+// cert and key from.
+type SecretRef struct {
+	Namespace string
+	Name      string
+	CertKey   string // defaults to "tls.crt"
+	KeyKey    string // defaults to "tls.key"
+}
+
+// NewLocalCASigner fetches the CA cert+key named by ref from ref.Namespace,
+// builds a cfssl local.Signer with a client-auth profile valid for expiry,
+// and returns a Signer backed by it.
+func NewLocalCASigner(ctx context.Context, clientset *kubernetes.Clientset, ref SecretRef, username string, expiry time.Duration) (*LocalCASigner, error) {
+	certKey := ref.CertKey
+	if certKey == "" {
+		certKey = "tls.crt"
+	}
+	keyKey := ref.KeyKey
+	if keyKey == "" {
+		keyKey = "tls.key"
+	}
+
+	secret, err := clientset.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("getting CA secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+
+	caCertPEM, ok := secret.Data[certKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no key %q", ref.Namespace, ref.Name, certKey)
+	}
+	caKeyPEM, ok := secret.Data[keyKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no key %q", ref.Namespace, ref.Name, keyKey)
+	}
+
+	caCert, err := helpers.ParseCertificatePEM(caCertPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CA certificate: %w", err)
+	}
+
+	caKey, err := parsePrivateKeyPEM(caKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CA key: %w", err)
+	}
+
+	policy := &cfsslconfig.Signing{
+		Default: &cfsslconfig.SigningProfile{
+			Usage:    []string{"client auth"},
+			Expiry:   expiry,
+			Backdate: 5 * time.Minute, // tolerate clock skew between this host and kube-apiserver
+		},
+	}
+
+	s, err := local.NewSigner(caKey, caCert, x509.SHA256WithRSA, policy)
+	if err != nil {
+		return nil, fmt.Errorf("building local CA signer: %w", err)
+	}
+
+	return &LocalCASigner{
+		Username: username,
+		signer:   s,
+	}, nil
+}
+
+// Sign implements Signer. notAfter is not used: the certificate's lifetime
+// is the profile's Expiry set at construction time.
+func (s *LocalCASigner) Sign(ctx context.Context, csrPEM []byte, notAfter time.Time) ([]byte, error) {
+	csr, err := helpers.ParseCSRPEM(csrPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CSR: %w", err)
+	}
+	if csr.Subject.CommonName != s.Username {
+		return nil, fmt.Errorf("refusing to sign: CSR CommonName %q does not match --username %q", csr.Subject.CommonName, s.Username)
+	}
+
+	certPEM, err := s.signer.Sign(cfsslsigner.SignRequest{Request: string(csrPEM)})
+	if err != nil {
+		return nil, fmt.Errorf("signing CSR: %w", err)
+	}
+
+	return certPEM, nil
+}
+
+// parsePrivateKeyPEM decodes a PEM-encoded RSA key in either PKCS#1 or
+// PKCS#8 form, as found in a TLS secret's tls.key.
+func parsePrivateKeyPEM(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("key is neither PKCS#1 nor PKCS#8: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("PKCS#8 key of type %T does not implement crypto.Signer", key)
+	}
+	return signer, nil
+}
@@ -0,0 +1,23 @@
+// Package signer provides pluggable backends for turning a CSR into a
+// signed certificate. The default backend drives the cluster's
+// certificates.k8s.io CSR + approval flow; the local-ca backend signs
+// in-process against a CA loaded from a Kubernetes Secret, for clusters
+// where cluster-admin approval permission isn't available.
+package signer
+
+import (
+	"context"
+	"time"
+)
+
+// Signer turns a PEM-encoded CSR into a PEM-encoded certificate valid until
+// notAfter.
+type Signer interface {
+	Sign(ctx context.Context, csrPEM []byte, notAfter time.Time) (certPEM []byte, err error)
+}
+
+// Backend names accepted by the --signer flag.
+const (
+	BackendK8sCSR  = "k8s-csr"
+	BackendLocalCA = "local-ca"
+)
@@ -0,0 +1,221 @@
+package userprovision
+
+import (
+	"context"
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+func toYAML(obj interface{}) (string, error) {
+	b, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return "---\n" + string(b), nil
+}
+
+// ApplyRBAC idempotently reconciles the Roles, ClusterRoles, RoleBindings,
+// and ClusterRoleBindings described by user against the cluster.
+func ApplyRBAC(ctx context.Context, clientset *kubernetes.Clientset, user UserSpec) error {
+	seenRoles := map[string]bool{}
+	for _, r := range user.Roles {
+		name := r.ObjectName(user.Name)
+		key := r.Namespace + "/" + name
+		if seenRoles[key] {
+			return fmt.Errorf("duplicate role name %q in namespace %q for user %q: set distinct name fields", name, r.Namespace, user.Name)
+		}
+		seenRoles[key] = true
+		if err := CreateOrUpdateRole(ctx, clientset, user.Name, r.Namespace, name, r.Rules); err != nil {
+			return fmt.Errorf("applying role %s for namespace %s: %w", name, r.Namespace, err)
+		}
+	}
+	seenClusterRoles := map[string]bool{}
+	for _, cr := range user.ClusterRoles {
+		name := cr.ObjectName(user.Name)
+		if seenClusterRoles[name] {
+			return fmt.Errorf("duplicate cluster role name %q for user %q: set distinct name fields", name, user.Name)
+		}
+		seenClusterRoles[name] = true
+		if err := CreateOrUpdateClusterRole(ctx, clientset, user.Name, name, cr.Rules); err != nil {
+			return fmt.Errorf("applying cluster role %s: %w", name, err)
+		}
+	}
+	seenRoleBindings := map[string]bool{}
+	for _, rb := range user.RoleBindings {
+		name := rb.ObjectName(user.Name)
+		key := rb.Namespace + "/" + name
+		if seenRoleBindings[key] {
+			return fmt.Errorf("duplicate role binding name %q in namespace %q for user %q: set distinct name fields", name, rb.Namespace, user.Name)
+		}
+		seenRoleBindings[key] = true
+		if err := CreateOrUpdateRoleBinding(ctx, clientset, user.Name, rb.Namespace, name, rb.RoleName, user.Groups); err != nil {
+			return fmt.Errorf("applying role binding %s for namespace %s: %w", name, rb.Namespace, err)
+		}
+	}
+	seenClusterRoleBindings := map[string]bool{}
+	for _, crb := range user.ClusterRoleBindings {
+		name := crb.ObjectName(user.Name)
+		if seenClusterRoleBindings[name] {
+			return fmt.Errorf("duplicate cluster role binding name %q for user %q: set distinct name fields", name, user.Name)
+		}
+		seenClusterRoleBindings[name] = true
+		if err := CreateOrUpdateClusterRoleBinding(ctx, clientset, user.Name, name, crb.ClusterRoleName, user.Groups); err != nil {
+			return fmt.Errorf("applying cluster role binding %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RenderRBACYAML returns the YAML of everything ApplyRBAC would create for
+// user, without touching the API, for --dry-run.
+func RenderRBACYAML(user UserSpec) (string, error) {
+	var out string
+	for _, r := range user.Roles {
+		role := &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: r.ObjectName(user.Name), Namespace: r.Namespace, Labels: ownerLabels(user.Name)},
+			Rules:      r.Rules,
+		}
+		doc, err := toYAML(role)
+		if err != nil {
+			return "", err
+		}
+		out += doc
+	}
+	for _, cr := range user.ClusterRoles {
+		clusterRole := &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: cr.ObjectName(user.Name), Labels: ownerLabels(user.Name)},
+			Rules:      cr.Rules,
+		}
+		doc, err := toYAML(clusterRole)
+		if err != nil {
+			return "", err
+		}
+		out += doc
+	}
+	for _, rb := range user.RoleBindings {
+		roleBinding := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: rb.ObjectName(user.Name), Namespace: rb.Namespace, Labels: ownerLabels(user.Name)},
+			Subjects:   bindingSubjects(user.Name, rb.Namespace, user.Groups),
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: rb.RoleName, APIGroup: "rbac.authorization.k8s.io"},
+		}
+		doc, err := toYAML(roleBinding)
+		if err != nil {
+			return "", err
+		}
+		out += doc
+	}
+	for _, crb := range user.ClusterRoleBindings {
+		clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: crb.ObjectName(user.Name), Labels: ownerLabels(user.Name)},
+			Subjects:   bindingSubjects(user.Name, "", user.Groups),
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: crb.ClusterRoleName, APIGroup: "rbac.authorization.k8s.io"},
+		}
+		doc, err := toYAML(clusterRoleBinding)
+		if err != nil {
+			return "", err
+		}
+		out += doc
+	}
+	return out, nil
+}
+
+// bindingSubjects builds the Subjects list CreateOrUpdateRoleBinding and
+// CreateOrUpdateClusterRoleBinding would apply for username: the User
+// itself (namespaced when namespace is set, matching CreateOrUpdateRoleBinding;
+// cluster-scoped with an explicit APIGroup otherwise, matching
+// CreateOrUpdateClusterRoleBinding), plus one Group subject per group the
+// user belongs to.
+func bindingSubjects(username, namespace string, groups []string) []rbacv1.Subject {
+	user := rbacv1.Subject{Kind: "User", Name: username, Namespace: namespace}
+	if namespace == "" {
+		user.APIGroup = "rbac.authorization.k8s.io"
+	}
+	subjects := []rbacv1.Subject{user}
+	for _, group := range groups {
+		subjects = append(subjects, rbacv1.Subject{Kind: "Group", Name: group, APIGroup: "rbac.authorization.k8s.io"})
+	}
+	return subjects
+}
+
+// Prune deletes Roles, ClusterRoles, RoleBindings, and ClusterRoleBindings
+// labeled userprovisioner.k8s/owner=<username> that are no longer present in
+// user's spec.
+func Prune(ctx context.Context, clientset *kubernetes.Clientset, user UserSpec) error {
+	selector := fmt.Sprintf("%s=%s", OwnerLabelKey, user.Name)
+	listOpts := metav1.ListOptions{LabelSelector: selector}
+
+	wantRoles := map[string]bool{}
+	for _, r := range user.Roles {
+		wantRoles[r.Namespace+"/"+r.ObjectName(user.Name)] = true
+	}
+	roles, err := clientset.RbacV1().Roles("").List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("listing owned roles: %w", err)
+	}
+	for _, role := range roles.Items {
+		if wantRoles[role.Namespace+"/"+role.Name] {
+			continue
+		}
+		if err := clientset.RbacV1().Roles(role.Namespace).Delete(ctx, role.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("pruning role %s/%s: %w", role.Namespace, role.Name, err)
+		}
+	}
+
+	wantClusterRoles := map[string]bool{}
+	for _, cr := range user.ClusterRoles {
+		wantClusterRoles[cr.ObjectName(user.Name)] = true
+	}
+	clusterRoles, err := clientset.RbacV1().ClusterRoles().List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("listing owned cluster roles: %w", err)
+	}
+	for _, cr := range clusterRoles.Items {
+		if wantClusterRoles[cr.Name] {
+			continue
+		}
+		if err := clientset.RbacV1().ClusterRoles().Delete(ctx, cr.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("pruning cluster role %s: %w", cr.Name, err)
+		}
+	}
+
+	wantRoleBindings := map[string]bool{}
+	for _, rb := range user.RoleBindings {
+		wantRoleBindings[rb.Namespace+"/"+rb.ObjectName(user.Name)] = true
+	}
+	roleBindings, err := clientset.RbacV1().RoleBindings("").List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("listing owned role bindings: %w", err)
+	}
+	for _, rb := range roleBindings.Items {
+		if wantRoleBindings[rb.Namespace+"/"+rb.Name] {
+			continue
+		}
+		if err := clientset.RbacV1().RoleBindings(rb.Namespace).Delete(ctx, rb.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("pruning role binding %s/%s: %w", rb.Namespace, rb.Name, err)
+		}
+	}
+
+	wantClusterRoleBindings := map[string]bool{}
+	for _, crb := range user.ClusterRoleBindings {
+		wantClusterRoleBindings[crb.ObjectName(user.Name)] = true
+	}
+	clusterRoleBindings, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, listOpts)
+	if err != nil {
+		return fmt.Errorf("listing owned cluster role bindings: %w", err)
+	}
+	for _, crb := range clusterRoleBindings.Items {
+		if wantClusterRoleBindings[crb.Name] {
+			continue
+		}
+		if err := clientset.RbacV1().ClusterRoleBindings().Delete(ctx, crb.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("pruning cluster role binding %s: %w", crb.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package userprovision
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func ownerLabels(username string) map[string]string {
+	return map[string]string{OwnerLabelKey: username}
+}
+
+// CreateOrUpdateRole creates the Role named name (see RoleSpec.ObjectName) in
+// namespace, or, if it already exists, updates its Rules in place when they
+// differ.
+func CreateOrUpdateRole(ctx context.Context, clientset *kubernetes.Clientset, username, namespace, name string, rules []rbacv1.PolicyRule) error {
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    ownerLabels(username),
+		},
+		Rules: rules,
+	}
+
+	_, err := clientset.RbacV1().Roles(namespace).Create(ctx, role, metav1.CreateOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := clientset.RbacV1().Roles(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting existing Role %s/%s: %w", namespace, name, err)
+	}
+	if reflect.DeepEqual(existing.Rules, rules) {
+		return nil
+	}
+	existing.Rules = rules
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	existing.Labels[OwnerLabelKey] = username
+	_, err = clientset.RbacV1().Roles(namespace).Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// CreateOrUpdateClusterRole creates the ClusterRole named name (see
+// ClusterRoleSpec.ObjectName), or updates its Rules in place if it exists.
+func CreateOrUpdateClusterRole(ctx context.Context, clientset *kubernetes.Clientset, username, name string, rules []rbacv1.PolicyRule) error {
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: ownerLabels(username),
+		},
+		Rules: rules,
+	}
+
+	_, err := clientset.RbacV1().ClusterRoles().Create(ctx, clusterRole, metav1.CreateOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := clientset.RbacV1().ClusterRoles().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting existing ClusterRole %s: %w", name, err)
+	}
+	if reflect.DeepEqual(existing.Rules, rules) {
+		return nil
+	}
+	existing.Rules = rules
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	existing.Labels[OwnerLabelKey] = username
+	_, err = clientset.RbacV1().ClusterRoles().Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// CreateOrUpdateRoleBinding binds username (and its groups, as Kind=Group
+// subjects) to roleName in namespace under the object name name (see
+// RoleBindingSpec.ObjectName), reconciling the Subjects/RoleRef if the
+// binding already exists.
+func CreateOrUpdateRoleBinding(ctx context.Context, clientset *kubernetes.Clientset, username, namespace, name, roleName string, groups []string) error {
+	subjects := []rbacv1.Subject{
+		{
+			Kind:      "User",
+			Name:      username,
+			Namespace: namespace,
+		},
+	}
+	for _, group := range groups {
+		subjects = append(subjects, rbacv1.Subject{
+			Kind:     "Group",
+			Name:     group,
+			APIGroup: "rbac.authorization.k8s.io",
+		})
+	}
+	roleRef := rbacv1.RoleRef{
+		Kind:     "Role",
+		Name:     roleName,
+		APIGroup: "rbac.authorization.k8s.io",
+	}
+
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    ownerLabels(username),
+		},
+		Subjects: subjects,
+		RoleRef:  roleRef,
+	}
+
+	_, err := clientset.RbacV1().RoleBindings(namespace).Create(ctx, roleBinding, metav1.CreateOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := clientset.RbacV1().RoleBindings(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting existing RoleBinding %s/%s: %w", namespace, name, err)
+	}
+	if reflect.DeepEqual(existing.Subjects, subjects) && reflect.DeepEqual(existing.RoleRef, roleRef) {
+		return nil
+	}
+	// RoleRef is immutable once set; a changed roleName needs a delete+recreate.
+	if !reflect.DeepEqual(existing.RoleRef, roleRef) {
+		if err := clientset.RbacV1().RoleBindings(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("deleting RoleBinding %s/%s for roleRef change: %w", namespace, name, err)
+		}
+		_, err = clientset.RbacV1().RoleBindings(namespace).Create(ctx, roleBinding, metav1.CreateOptions{})
+		return err
+	}
+	existing.Subjects = subjects
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	existing.Labels[OwnerLabelKey] = username
+	_, err = clientset.RbacV1().RoleBindings(namespace).Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// CreateOrUpdateClusterRoleBinding binds username (and its groups, as
+// Kind=Group subjects) to clusterRoleName cluster-wide under the object
+// name name (see ClusterRoleBindingSpec.ObjectName), reconciling Subjects
+// if the binding already exists.
+func CreateOrUpdateClusterRoleBinding(ctx context.Context, clientset *kubernetes.Clientset, username, name, clusterRoleName string, groups []string) error {
+	subjects := []rbacv1.Subject{
+		{
+			Kind:     "User",
+			Name:     username,
+			APIGroup: "rbac.authorization.k8s.io",
+		},
+	}
+	for _, group := range groups {
+		subjects = append(subjects, rbacv1.Subject{
+			Kind:     "Group",
+			Name:     group,
+			APIGroup: "rbac.authorization.k8s.io",
+		})
+	}
+	roleRef := rbacv1.RoleRef{
+		Kind:     "ClusterRole",
+		Name:     clusterRoleName,
+		APIGroup: "rbac.authorization.k8s.io",
+	}
+
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: ownerLabels(username),
+		},
+		Subjects: subjects,
+		RoleRef:  roleRef,
+	}
+
+	_, err := clientset.RbacV1().ClusterRoleBindings().Create(ctx, clusterRoleBinding, metav1.CreateOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := clientset.RbacV1().ClusterRoleBindings().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting existing ClusterRoleBinding %s: %w", name, err)
+	}
+	if reflect.DeepEqual(existing.Subjects, subjects) && reflect.DeepEqual(existing.RoleRef, roleRef) {
+		return nil
+	}
+	if !reflect.DeepEqual(existing.RoleRef, roleRef) {
+		if err := clientset.RbacV1().ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("deleting ClusterRoleBinding %s for roleRef change: %w", name, err)
+		}
+		_, err = clientset.RbacV1().ClusterRoleBindings().Create(ctx, clusterRoleBinding, metav1.CreateOptions{})
+		return err
+	}
+	existing.Subjects = subjects
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	existing.Labels[OwnerLabelKey] = username
+	_, err = clientset.RbacV1().ClusterRoleBindings().Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
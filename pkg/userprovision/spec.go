@@ -0,0 +1,154 @@
+package userprovision
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// OwnerLabelKey marks every Role/ClusterRole/RoleBinding/ClusterRoleBinding
+// created on behalf of a user, so Prune can find objects that used
+// to belong to a user's spec and no longer do.
+const OwnerLabelKey = "userprovisioner.k8s/owner"
+
+// Spec is the top-level shape of a --spec users.yaml file: a declarative
+// list of users and the access each of them should have, replacing the
+// comma/colon-delimited --role-rules/--role-bindings flags.
+type Spec struct {
+	Users []UserSpec `json:"users"`
+}
+
+// UserSpec describes one user's credentials and RBAC grants.
+type UserSpec struct {
+	Name                string                   `json:"name"`
+	Groups              []string                 `json:"groups,omitempty"`
+	TTL                 string                   `json:"ttl,omitempty"`
+	Roles               []RoleSpec               `json:"roles,omitempty"`
+	ClusterRoles        []ClusterRoleSpec        `json:"clusterRoles,omitempty"`
+	RoleBindings        []RoleBindingSpec        `json:"roleBindings,omitempty"`
+	ClusterRoleBindings []ClusterRoleBindingSpec `json:"clusterRoleBindings,omitempty"`
+}
+
+// RoleSpec is a namespaced Role to create for the user. Name disambiguates a
+// user with more than one Role entry in the same namespace; it may be left
+// empty when a user has only one per namespace.
+type RoleSpec struct {
+	Name      string              `json:"name,omitempty"`
+	Namespace string              `json:"namespace"`
+	Rules     []rbacv1.PolicyRule `json:"rules"`
+}
+
+// ObjectName returns the Role object name this spec applies to for username:
+// "<username>-role" when Name is unset, or "<username>-<name>-role" when a
+// user has multiple entries in the same namespace that need distinct names.
+func (r RoleSpec) ObjectName(username string) string {
+	if r.Name == "" {
+		return username + "-role"
+	}
+	return username + "-" + r.Name + "-role"
+}
+
+// ClusterRoleSpec is a cluster-scoped ClusterRole to create for the user.
+// Name disambiguates a user with more than one ClusterRole entry; it may be
+// left empty when a user has only one.
+type ClusterRoleSpec struct {
+	Name  string              `json:"name,omitempty"`
+	Rules []rbacv1.PolicyRule `json:"rules"`
+}
+
+// ObjectName returns the ClusterRole object name this spec applies to for
+// username: "<username>-clusterrole" when Name is unset, or
+// "<username>-<name>-clusterrole" when a user has multiple entries that
+// need distinct names.
+func (c ClusterRoleSpec) ObjectName(username string) string {
+	if c.Name == "" {
+		return username + "-clusterrole"
+	}
+	return username + "-" + c.Name + "-clusterrole"
+}
+
+// RoleBindingSpec binds the user to an existing Role in a namespace. Name
+// disambiguates a user with more than one RoleBinding entry in the same
+// namespace; it may be left empty when a user has only one per namespace.
+type RoleBindingSpec struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace"`
+	RoleName  string `json:"roleName"`
+}
+
+// ObjectName returns the RoleBinding object name this spec applies to for
+// username, mirroring RoleSpec.ObjectName.
+func (r RoleBindingSpec) ObjectName(username string) string {
+	if r.Name == "" {
+		return username + "-rolebinding"
+	}
+	return username + "-" + r.Name + "-rolebinding"
+}
+
+// ClusterRoleBindingSpec binds the user to an existing ClusterRole. Name
+// disambiguates a user with more than one ClusterRoleBinding entry; it may
+// be left empty when a user has only one.
+type ClusterRoleBindingSpec struct {
+	Name            string `json:"name,omitempty"`
+	ClusterRoleName string `json:"clusterRoleName"`
+}
+
+// ObjectName returns the ClusterRoleBinding object name this spec applies
+// to for username, mirroring ClusterRoleSpec.ObjectName.
+func (c ClusterRoleBindingSpec) ObjectName(username string) string {
+	if c.Name == "" {
+		return username + "-clusterrolebinding"
+	}
+	return username + "-" + c.Name + "-clusterrolebinding"
+}
+
+// RoleNames returns the Role object names ApplyRBAC creates for the user,
+// for recording in a ProvisionedUser's tracked Spec.Roles.
+func (u UserSpec) RoleNames() []string {
+	names := make([]string, len(u.Roles))
+	for i, r := range u.Roles {
+		names[i] = r.ObjectName(u.Name)
+	}
+	return names
+}
+
+// ClusterRoleNames returns the ClusterRole object names ApplyRBAC creates
+// for the user, for recording in a ProvisionedUser's tracked
+// Spec.ClusterRoles.
+func (u UserSpec) ClusterRoleNames() []string {
+	names := make([]string, len(u.ClusterRoles))
+	for i, cr := range u.ClusterRoles {
+		names[i] = cr.ObjectName(u.Name)
+	}
+	return names
+}
+
+// ParsedTTL returns the user's TTL as a Duration, or zero if unset.
+func (u UserSpec) ParsedTTL() (time.Duration, error) {
+	if u.TTL == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(u.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("parsing ttl %q for user %q: %w", u.TTL, u.Name, err)
+	}
+	return d, nil
+}
+
+// LoadSpec reads and YAML-unmarshals a users.yaml spec file.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading spec %s: %w", path, err)
+	}
+
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parsing spec %s: %w", path, err)
+	}
+
+	return &spec, nil
+}
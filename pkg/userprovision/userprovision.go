@@ -0,0 +1,254 @@
+// Package userprovision implements the core key/CSR/kubeconfig issuance flow
+// used by the kubectl-create-user CLI. It is factored out of main() so the
+// same flow can be embedded in a long-running service (e.g. a gRPC/HTTP
+// credential broker) instead of only being reachable as a one-shot CLI.
+package userprovision
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/hamido95/go-kubernetes-user-creator/pkg/signer"
+)
+
+// UserRequest describes the credentials a caller wants minted for a user.
+type UserRequest struct {
+	Username string
+	DirName  string
+	// Groups are mapped onto the CSR's Subject Organization fields, which
+	// Kubernetes treats as the user's group membership for RBAC purposes.
+	Groups []string
+	// TTL is how long the issued certificate should remain valid. When zero,
+	// Expiration is used instead (long-lived, year-scale certs).
+	TTL time.Duration
+	// Expiration is the legacy long-lived cert duration, kept for callers
+	// that don't set TTL.
+	Expiration     time.Duration
+	ClusterName    string
+	KubeConfigPath string
+	SignerName     string
+}
+
+// IssuedCredentials is the result of a successful provisioning run.
+type IssuedCredentials struct {
+	Username       string
+	KeyPath        string
+	CertPath       string
+	KubeconfigPath string
+	CertPEM        []byte
+}
+
+// Provisioner drives the key generation, CSR submission/approval, and
+// kubeconfig assembly flow against a Kubernetes cluster.
+type Provisioner struct {
+	Clientset *kubernetes.Clientset
+	// Signer selects the backend used to turn a CSR into a signed
+	// certificate. When nil, Provision falls back to the default
+	// certificates.k8s.io CSR + approval flow (signer.BackendK8sCSR).
+	Signer signer.Signer
+}
+
+// NewProvisioner wraps an existing Kubernetes clientset for provisioning use.
+func NewProvisioner(clientset *kubernetes.Clientset) *Provisioner {
+	return &Provisioner{Clientset: clientset}
+}
+
+// GenerateKey creates an RSA-2048 private key and writes it to
+// <dir>/<username>.key in PEM form.
+func GenerateKey(req UserRequest) (*rsa.PrivateKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	keyFile, err := os.Create(fmt.Sprintf("%s/%s.key", req.DirName, req.Username))
+	if err != nil {
+		return nil, err
+	}
+	defer keyFile.Close()
+
+	if err := pem.Encode(keyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// CreateCSR builds a certificate request for the given user, embedding
+// their groups as Subject Organization values, and writes it to
+// <dir>/<username>.csr in PEM form.
+func CreateCSR(req UserRequest, key *rsa.PrivateKey) ([]byte, error) {
+	csrTemplate := x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   req.Username,
+			Organization: req.Groups,
+		},
+	}
+
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &csrTemplate, key)
+	if err != nil {
+		return nil, err
+	}
+
+	csrFile, err := os.Create(fmt.Sprintf("%s/%s.csr", req.DirName, req.Username))
+	if err != nil {
+		return nil, err
+	}
+	defer csrFile.Close()
+
+	if err := pem.Encode(csrFile, &pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}); err != nil {
+		return nil, err
+	}
+
+	return csrBytes, nil
+}
+
+// expiry returns the requested cert lifetime, preferring TTL (short-lived
+// mode) over the legacy Expiration field.
+func (req UserRequest) expiry() time.Duration {
+	if req.TTL > 0 {
+		return req.TTL
+	}
+	return req.Expiration
+}
+
+// SubmitAndApproveCSR signs csrBytes using p.Signer, defaulting to the
+// certificates.k8s.io CSR + approval flow (signer.K8sCSRSigner) when no
+// Signer backend was configured.
+func (p *Provisioner) SubmitAndApproveCSR(ctx context.Context, req UserRequest, csrBytes []byte) ([]byte, error) {
+	s := p.Signer
+	if s == nil {
+		s = signer.NewK8sCSRSigner(p.Clientset, req.Username, req.SignerName)
+	}
+
+	encodedCSR := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes})
+	return s.Sign(ctx, encodedCSR, time.Now().Add(req.expiry()))
+}
+
+// APIServerCA returns the CA bundle the cluster's kube-apiserver presents,
+// read off the current rest.Config. It is embedded as
+// certificate-authority-data in issued kubeconfigs instead of copying a
+// pre-existing file.
+func APIServerCA(caData []byte) []byte {
+	return caData
+}
+
+// BuildKubeconfig assembles a standalone kubeconfig for the user: it clones
+// baseKubeconfig, sets the user's credentials (with the signed cert chained
+// to intermediateCA so verifiers that only trust the root can still build a
+// path), points certificate-authority-data at apiServerCA, and switches the
+// current context to the user. The kubeconfig is assembled at a temporary
+// path and renamed into place only once every step succeeds, so renewing an
+// existing user's credentials can't leave a partially-written kubeconfig
+// behind if one of the kubectl calls below fails partway through.
+func BuildKubeconfig(req UserRequest, baseKubeconfigPath string, certPEM, intermediateCA, apiServerCA []byte) (string, error) {
+	kubeconfig, err := os.ReadFile(baseKubeconfigPath)
+	if err != nil {
+		return "", fmt.Errorf("reading kubeconfig: %w", err)
+	}
+
+	kubeconfigPath := fmt.Sprintf("%s/%s-config", req.DirName, req.Username)
+	tmpPath := kubeconfigPath + ".tmp"
+	if err := os.WriteFile(tmpPath, kubeconfig, 0644); err != nil {
+		return "", fmt.Errorf("writing user kubeconfig: %w", err)
+	}
+	defer os.Remove(tmpPath) // no-op once the rename below has moved it into place
+
+	chain := append([]byte{}, certPEM...)
+	if len(intermediateCA) > 0 {
+		chain = append(chain, '\n')
+		chain = append(chain, intermediateCA...)
+	}
+
+	certPath := fmt.Sprintf("%s/%s.crt", req.DirName, req.Username)
+	if err := os.WriteFile(certPath, chain, 0644); err != nil {
+		return "", fmt.Errorf("writing certificate chain: %w", err)
+	}
+
+	if len(apiServerCA) > 0 {
+		caPath := fmt.Sprintf("%s/%s-ca.crt", req.DirName, req.Username)
+		if err := os.WriteFile(caPath, apiServerCA, 0644); err != nil {
+			return "", fmt.Errorf("writing api-server CA: %w", err)
+		}
+		cmd := exec.Command("kubectl", "--kubeconfig", tmpPath, "config", "set-cluster", req.ClusterName,
+			"--certificate-authority", caPath,
+			"--embed-certs=true")
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("setting cluster CA in kubeconfig: %w", err)
+		}
+	}
+
+	cmd := exec.Command("kubectl", "--kubeconfig", tmpPath, "config", "set-credentials", req.Username,
+		"--client-key", fmt.Sprintf("%s/%s.key", req.DirName, req.Username),
+		"--client-certificate", certPath,
+		"--embed-certs=true")
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("setting credentials in kubeconfig: %w", err)
+	}
+
+	cmd = exec.Command("kubectl", "--kubeconfig", tmpPath, "config", "set-context", req.Username,
+		"--cluster", req.ClusterName,
+		"--user", req.Username)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("setting context in kubeconfig: %w", err)
+	}
+
+	cmd = exec.Command("kubectl", "--kubeconfig", tmpPath, "config", "use-context", req.Username)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("using context in kubeconfig: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, kubeconfigPath); err != nil {
+		return "", fmt.Errorf("installing new kubeconfig: %w", err)
+	}
+
+	return kubeconfigPath, nil
+}
+
+// Provision runs the full key -> CSR -> approval -> kubeconfig flow and
+// returns the resulting credentials. intermediateCA and apiServerCA may be
+// nil for callers that don't need chain assembly (e.g. the legacy one-shot
+// long-lived flow).
+func (p *Provisioner) Provision(ctx context.Context, req UserRequest, baseKubeconfigPath string, intermediateCA, apiServerCA []byte) (*IssuedCredentials, error) {
+	if err := os.MkdirAll(req.DirName, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("creating dir: %w", err)
+	}
+
+	key, err := GenerateKey(req)
+	if err != nil {
+		return nil, fmt.Errorf("generating RSA key: %w", err)
+	}
+
+	csrBytes, err := CreateCSR(req, key)
+	if err != nil {
+		return nil, fmt.Errorf("generating CSR: %w", err)
+	}
+
+	certPEM, err := p.SubmitAndApproveCSR(ctx, req, csrBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfigPath, err := BuildKubeconfig(req, baseKubeconfigPath, certPEM, intermediateCA, apiServerCA)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssuedCredentials{
+		Username:       req.Username,
+		KeyPath:        fmt.Sprintf("%s/%s.key", req.DirName, req.Username),
+		CertPath:       fmt.Sprintf("%s/%s.crt", req.DirName, req.Username),
+		KubeconfigPath: kubeconfigPath,
+		CertPEM:        certPEM,
+	}, nil
+}